@@ -0,0 +1,266 @@
+// Command snapshotctl inspects, diffs, and prunes the *.snapshots.json
+// files written by the snapshotter package, without needing to run `go
+// test -rewriteSnapshots` or hand-edit the JSON.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ChenJesse/go/snapshotter"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "ls":
+		err = runLs(os.Args[2:])
+	case "show":
+		err = runShow(os.Args[2:])
+	case "diff":
+		err = runDiff(os.Args[2:])
+	case "prune":
+		err = runPrune(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "snapshotctl:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage:
+  snapshotctl ls <dir>
+  snapshotctl show <file> [name]
+  snapshotctl diff <a> <b>
+  snapshotctl prune <dir>`)
+}
+
+// runLs lists the snapshot names and the size of their rendered values for
+// every *.snapshots.json file in dir.
+func runLs(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: snapshotctl ls <dir>")
+	}
+	files, err := snapshotFiles(args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		snapshots, err := snapshotter.ReadSnapshotFile(file)
+		if err != nil {
+			fmt.Printf("%s: error: %s\n", file, err)
+			continue
+		}
+		fmt.Println(file)
+		for _, snap := range snapshots {
+			fmt.Printf("  %-40s %6d bytes\n", snap.Name, len(snapshotter.CoerceToString(snap.Values)))
+		}
+	}
+	return nil
+}
+
+// runShow pretty-prints a single snapshot from file, or every snapshot in
+// the file if name is omitted.
+func runShow(args []string) error {
+	if len(args) != 1 && len(args) != 2 {
+		return fmt.Errorf("usage: snapshotctl show <file> [name]")
+	}
+	file := args[0]
+	var name string
+	if len(args) == 2 {
+		name = args[1]
+	}
+
+	snapshots, err := snapshotter.ReadSnapshotFile(file)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, snap := range snapshots {
+		if name != "" && snap.Name != name {
+			continue
+		}
+		found = true
+		fmt.Printf("=== %s ===\n%s\n", snap.Name, snapshotter.CoerceToString(snap.Values))
+	}
+	if name != "" && !found {
+		return fmt.Errorf("no snapshot named %q in %s", name, file)
+	}
+	return nil
+}
+
+// runDiff renders a structural diff between the snapshots in a and b,
+// matched up by name.
+func runDiff(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: snapshotctl diff <a> <b>")
+	}
+	aFile, bFile := args[0], args[1]
+
+	aSnapshots, err := snapshotter.ReadSnapshotFile(aFile)
+	if err != nil {
+		return err
+	}
+	bSnapshots, err := snapshotter.ReadSnapshotFile(bFile)
+	if err != nil {
+		return err
+	}
+
+	bByName := make(map[string]snapshotter.Snapshot, len(bSnapshots))
+	for _, snap := range bSnapshots {
+		bByName[snap.Name] = snap
+	}
+
+	differed := false
+	for _, aSnap := range aSnapshots {
+		bSnap, ok := bByName[aSnap.Name]
+		if !ok {
+			fmt.Printf("- %s (only in %s)\n", aSnap.Name, aFile)
+			differed = true
+			continue
+		}
+		delete(bByName, aSnap.Name)
+
+		if diff := snapshotter.DiffString(aSnap.Values, bSnap.Values); diff != "" {
+			fmt.Printf("%s differs:\n%s\n", aSnap.Name, diff)
+			differed = true
+		}
+	}
+	for name := range bByName {
+		fmt.Printf("+ %s (only in %s)\n", name, bFile)
+		differed = true
+	}
+	if differed {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// runPrune deletes snapshot files in dir whose corresponding TestXxx no
+// longer exists in the package above dir, as reported by `go test -list`.
+func runPrune(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "print what would be removed without removing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: snapshotctl prune [-dry-run] <dir>")
+	}
+	dir := fs.Arg(0)
+
+	files, err := snapshotFiles(dir)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	testNames, err := listTests(filepath.Dir(dir))
+	if err != nil {
+		return err
+	}
+	live := make(map[string]bool, len(testNames))
+	for _, name := range testNames {
+		live[name] = true
+	}
+
+	for _, file := range files {
+		if isLive(file, live) {
+			continue
+		}
+		test := testNameForFile(file)
+		if *dryRun {
+			fmt.Printf("would remove %s (no matching test %s)\n", file, test)
+			continue
+		}
+		fmt.Printf("removing %s (no matching test %s)\n", file, test)
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func snapshotFiles(dir string) ([]string, error) {
+	return filepath.Glob(filepath.Join(dir, "*.snapshots.json"))
+}
+
+// testNameForFile recovers the root TestXxx function name that produced
+// file, reversing Snapshotter.Verify's sanitizing of "/" (subtests) and
+// ":" into "-". It's only used for the log message above -- isLive does
+// the actual keep/prune decision, since a NewNamed suffix or an
+// underscore in the test name itself can also produce a "-"-free base
+// that this can't perfectly reverse.
+func testNameForFile(file string) string {
+	base := strings.TrimSuffix(filepath.Base(file), ".snapshots.json")
+	if idx := strings.Index(base, "-"); idx >= 0 {
+		return base[:idx]
+	}
+	return base
+}
+
+// isLive reports whether file could plausibly have been produced by one of
+// the live test names. Snapshotter.Verify's filename is the sanitized test
+// name optionally followed by "_" + a NewNamed suffix, and both "_" and the
+// sanitized name itself can contain characters that also appear in a Go
+// identifier, so instead of guessing a single split point, this checks
+// every prefix of the base ending just before a "-" or "_" (plus the full
+// base) against the live set.
+func isLive(file string, live map[string]bool) bool {
+	base := strings.TrimSuffix(filepath.Base(file), ".snapshots.json")
+	if live[base] {
+		return true
+	}
+	for i, r := range base {
+		if (r == '-' || r == '_') && live[base[:i]] {
+			return true
+		}
+	}
+	return false
+}
+
+// listTests runs `go test -list .*` against the package in dir and returns
+// the matched test names.
+func listTests(dir string) ([]string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	if !strings.HasPrefix(dir, ".") && !strings.HasPrefix(dir, "/") {
+		dir = "./" + dir
+	}
+
+	cmd := exec.Command("go", "test", "-list", ".*", dir)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go test -list failed for %s: %w", dir, err)
+	}
+
+	var names []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.ContainsAny(line, " \t") {
+			// Skip blank lines and the trailing "ok  <pkg>  <time>" summary.
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, nil
+}
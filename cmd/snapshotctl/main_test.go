@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestIsLive(t *testing.T) {
+	live := map[string]bool{"TestFoo": true, "TestFooBar": true}
+
+	tests := []struct {
+		name string
+		file string
+		want bool
+	}{
+		{"plain test", "testdata/TestFoo.snapshots.json", true},
+		{"subtest", "testdata/TestFoo-Bar.snapshots.json", true},
+		{"NewNamed suffix", "testdata/TestFoo_v2.snapshots.json", true},
+		{"subtest of a NewNamed snapshotter", "testdata/TestFoo-Bar_v2.snapshots.json", true},
+		{"removed test", "testdata/TestBaz.snapshots.json", false},
+		{"name is a prefix of a live test, but isn't itself live", "testdata/TestFooBa.snapshots.json", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isLive(tc.file, live); got != tc.want {
+				t.Errorf("isLive(%q) = %v, want %v", tc.file, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTestNameForFile(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"testdata/TestFoo.snapshots.json", "TestFoo"},
+		{"testdata/TestFoo-Bar.snapshots.json", "TestFoo"},
+		{"testdata/TestFoo_v2.snapshots.json", "TestFoo_v2"},
+	}
+
+	for _, tc := range tests {
+		if got := testNameForFile(tc.file); got != tc.want {
+			t.Errorf("testNameForFile(%q) = %q, want %q", tc.file, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package snapshotter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+)
+
+// currentSchemaVersion is the envelope format version written by this
+// package. Bump it whenever the envelope itself (as opposed to the encoding
+// of individual values) changes shape, and add a new entry to
+// snapshotFileReaders so older envelopes can still be read.
+const currentSchemaVersion = 1
+
+// currentSnapshotSchemaVersion is stamped onto every snapshot recorded by
+// this version of the package, independent of the envelope's version. It
+// exists so a future reader can tell, per snapshot, whether a value needs a
+// different decoding strategy (e.g. a per-value encoding hint or a binary
+// blob) without having to bump the envelope version for the whole file.
+const currentSnapshotSchemaVersion = 1
+
+// wireSnapshot is how a snapshot is actually persisted. Each value is
+// encoded through the Serializer named by Serializer (its Ext()), not
+// through encoding/json directly -- that's what lets WithSerializer(YAML)
+// (or any future non-JSON serializer) store values, like
+// map[interface{}]interface{}, that encoding/json can't marshal at all. The
+// envelope itself stays plain JSON regardless of which Serializer is in use.
+type wireSnapshot struct {
+	Name          string   `json:"Name"`
+	SchemaVersion int      `json:"SchemaVersion,omitempty"`
+	Serializer    string   `json:"Serializer,omitempty"`
+	Values        []string `json:"Values"`
+}
+
+// snapshotFile is the on-disk envelope around a slice of snapshots. The CRC
+// guards against silently corrupted files (truncation, a bad merge, manual
+// edits) producing a misleading diff instead of a loud failure.
+type snapshotFile struct {
+	Version   int             `json:"version"`
+	CRC32     string          `json:"crc32"`
+	Snapshots []*wireSnapshot `json:"snapshots"`
+}
+
+// snapshotFileReader decodes and validates the contents of a snapshot file
+// at a specific envelope version.
+type snapshotFileReader func(data []byte) ([]*snapshot, error)
+
+// snapshotFileReaders is keyed by snapshotFile.Version, so a future
+// incompatible envelope change can be introduced by adding a reader here
+// rather than breaking the ones already on disk.
+var snapshotFileReaders = map[int]snapshotFileReader{
+	1: readSnapshotFileV1,
+}
+
+func readSnapshotFileV1(data []byte) ([]*snapshot, error) {
+	var file snapshotFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	canonical, err := json.Marshal(file.Snapshots)
+	if err != nil {
+		return nil, err
+	}
+	if got := fmt.Sprintf("%08x", crc32.ChecksumIEEE(canonical)); got != file.CRC32 {
+		if start, end, ok := snapshotsByteRange(data); ok {
+			return nil, fmt.Errorf("crc32 mismatch (want %s, got %s) in bytes [%d:%d) of the \"snapshots\" field; re-run with -rewriteSnapshots to regenerate", file.CRC32, got, start, end)
+		}
+		return nil, fmt.Errorf("crc32 mismatch (want %s, got %s); re-run with -rewriteSnapshots to regenerate", file.CRC32, got)
+	}
+
+	snapshots := make([]*snapshot, len(file.Snapshots))
+	for i, wire := range file.Snapshots {
+		serializer := serializerByExt(wire.Serializer)
+		values := make([]interface{}, len(wire.Values))
+		for j, raw := range wire.Values {
+			value, err := serializer.Unmarshal([]byte(raw))
+			if err != nil {
+				return nil, fmt.Errorf("%s: error unmarshaling value %d with the %q serializer: %w", wire.Name, j, wire.Serializer, err)
+			}
+			values[j] = value
+		}
+		snapshots[i] = &snapshot{
+			Name:          wire.Name,
+			Values:        values,
+			SchemaVersion: wire.SchemaVersion,
+		}
+	}
+
+	return snapshots, nil
+}
+
+// encodeSnapshotFile wraps snapshots in the current envelope, encoding each
+// value through serializer (so a non-default Serializer actually affects
+// what lands on disk) and computing the CRC over the canonical
+// (non-indented) encoding of the wire snapshots so it doesn't depend on
+// indentation.
+func encodeSnapshotFile(snapshots []*snapshot, serializer Serializer) ([]byte, error) {
+	wireSnapshots := make([]*wireSnapshot, len(snapshots))
+	for i, snap := range snapshots {
+		values := make([]string, len(snap.Values))
+		for j, value := range snap.Values {
+			encoded, err := serializer.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: error marshaling value %d: %w", snap.Name, j, err)
+			}
+			values[j] = string(encoded)
+		}
+		wireSnapshots[i] = &wireSnapshot{
+			Name:          snap.Name,
+			SchemaVersion: snap.SchemaVersion,
+			Serializer:    serializer.Ext(),
+			Values:        values,
+		}
+	}
+
+	canonical, err := json.Marshal(wireSnapshots)
+	if err != nil {
+		return nil, err
+	}
+	file := &snapshotFile{
+		Version:   currentSchemaVersion,
+		CRC32:     fmt.Sprintf("%08x", crc32.ChecksumIEEE(canonical)),
+		Snapshots: wireSnapshots,
+	}
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// decodeSnapshotFile reads the envelope header to find its version, then
+// dispatches to the matching reader in snapshotFileReaders.
+func decodeSnapshotFile(data []byte) ([]*snapshot, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	reader, ok := snapshotFileReaders[header.Version]
+	if !ok {
+		return nil, fmt.Errorf("unsupported snapshot file version %d; re-run with -rewriteSnapshots to regenerate", header.Version)
+	}
+	return reader(data)
+}
+
+// snapshotsByteRange finds the byte range of the "snapshots" array within a
+// raw envelope, so corruption can be reported with the offending range
+// instead of just "somewhere in this file".
+func snapshotsByteRange(raw []byte) (start, end int, ok bool) {
+	idx := bytes.Index(raw, []byte(`"snapshots"`))
+	if idx < 0 {
+		return 0, 0, false
+	}
+	colon := bytes.IndexByte(raw[idx:], ':')
+	if colon < 0 {
+		return 0, 0, false
+	}
+	start = idx + colon + 1
+	for start < len(raw) && (raw[start] == ' ' || raw[start] == '\n' || raw[start] == '\t' || raw[start] == '\r') {
+		start++
+	}
+	if start >= len(raw) || raw[start] != '[' {
+		return 0, 0, false
+	}
+
+	depth := 0
+	for i := start; i < len(raw); i++ {
+		switch raw[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return start, i + 1, true
+			}
+		}
+	}
+	return 0, 0, false
+}
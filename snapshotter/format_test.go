@@ -0,0 +1,83 @@
+package snapshotter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecodeSnapshotFileRoundTrip(t *testing.T) {
+	snapshots := []*snapshot{
+		{Name: "one", Values: []interface{}{"a"}, SchemaVersion: currentSnapshotSchemaVersion},
+		{Name: "two", Values: []interface{}{float64(2)}, SchemaVersion: currentSnapshotSchemaVersion},
+	}
+
+	data, err := encodeSnapshotFile(snapshots, JSONSerializer)
+	if err != nil {
+		t.Fatalf("error encoding snapshot file: %s", err)
+	}
+
+	decoded, err := decodeSnapshotFile(data)
+	if err != nil {
+		t.Fatalf("error decoding snapshot file: %s", err)
+	}
+	if len(decoded) != len(snapshots) {
+		t.Fatalf("expected %d snapshots, got %d", len(snapshots), len(decoded))
+	}
+	if decoded[0].Name != "one" || decoded[1].Name != "two" {
+		t.Fatalf("snapshots decoded out of order: %+v", decoded)
+	}
+}
+
+func TestDecodeSnapshotFileDetectsCRCMismatch(t *testing.T) {
+	data, err := encodeSnapshotFile([]*snapshot{{Name: "one", Values: []interface{}{"a"}}}, JSONSerializer)
+	if err != nil {
+		t.Fatalf("error encoding snapshot file: %s", err)
+	}
+
+	// Flip the name of the recorded snapshot without touching the stored
+	// CRC, simulating on-disk corruption.
+	corrupted := []byte(strings.Replace(string(data), `"Name": "one"`, `"Name": "two"`, 1))
+	if _, err := decodeSnapshotFile(corrupted); err == nil {
+		t.Fatalf("expected a corrupted snapshot file to fail to decode")
+	}
+}
+
+func TestDecodeSnapshotFileRejectsUnknownVersion(t *testing.T) {
+	_, err := decodeSnapshotFile([]byte(`{"version":99,"crc32":"0","snapshots":[]}`))
+	if err == nil {
+		t.Fatalf("expected an unknown version to be rejected")
+	}
+}
+
+// TestEncodeDecodeSnapshotFileRoundTripYAML covers the case JSONSerializer
+// can't represent at all: yaml.Unmarshal into interface{} always produces
+// map[interface{}]interface{}, which encoding/json refuses to marshal. Each
+// value must be encoded through the configured Serializer, not through
+// encoding/json directly, for this to round trip.
+func TestEncodeDecodeSnapshotFileRoundTripYAML(t *testing.T) {
+	value, err := serializerRoundTrip(YAMLSerializer, map[string]interface{}{"a": 1, "b": "two"})
+	if err != nil {
+		t.Fatalf("error round tripping value: %s", err)
+	}
+	if _, ok := value.(map[interface{}]interface{}); !ok {
+		t.Fatalf("expected yaml round trip to produce map[interface{}]interface{}, got %T", value)
+	}
+
+	snapshots := []*snapshot{{Name: "one", Values: []interface{}{value}}}
+	data, err := encodeSnapshotFile(snapshots, YAMLSerializer)
+	if err != nil {
+		t.Fatalf("error encoding snapshot file with YAMLSerializer: %s", err)
+	}
+
+	decoded, err := decodeSnapshotFile(data)
+	if err != nil {
+		t.Fatalf("error decoding snapshot file: %s", err)
+	}
+	got, ok := decoded[0].Values[0].(map[interface{}]interface{})
+	if !ok {
+		t.Fatalf("expected a map[interface{}]interface{}, got %T", decoded[0].Values[0])
+	}
+	if got["a"] != 1 || got["b"] != "two" {
+		t.Fatalf("unexpected decoded value: %+v", got)
+	}
+}
@@ -0,0 +1,65 @@
+package snapshotter
+
+import "regexp"
+
+// Matcher transforms a recorded value before it is written to or compared
+// against the snapshot file, letting callers ignore or normalize dynamic
+// data (timestamps, UUIDs, request IDs, ...) that would otherwise cause the
+// golden file to churn on every run.
+//
+// Matchers run at Snapshot time, so both the value written during
+// -rewriteSnapshots and the value captured during a normal run go through
+// the same transformation.
+type Matcher func(interface{}) interface{}
+
+// IgnoreFields returns a Matcher that removes the named top-level keys from
+// map-shaped values (as produced by the JSON/YAML serializers). Values that
+// aren't maps are returned unchanged.
+func IgnoreFields(fields ...string) Matcher {
+	return func(value interface{}) interface{} {
+		if m, ok := value.(map[interface{}]interface{}); ok {
+			out := make(map[interface{}]interface{}, len(m))
+			for k, v := range m {
+				out[k] = v
+			}
+			for _, field := range fields {
+				delete(out, field)
+			}
+			return out
+		}
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		out := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			out[k] = v
+		}
+		for _, field := range fields {
+			delete(out, field)
+		}
+		return out
+	}
+}
+
+// MatchRegexp returns a Matcher that replaces any string value matching
+// pattern with a fixed placeholder, so dynamic strings like generated IDs
+// can be snapshotted without churning the golden file every run.
+func MatchRegexp(pattern string) Matcher {
+	re := regexp.MustCompile(pattern)
+	placeholder := "<matches " + pattern + ">"
+	return func(value interface{}) interface{} {
+		str, ok := value.(string)
+		if !ok || !re.MatchString(str) {
+			return value
+		}
+		return placeholder
+	}
+}
+
+func applyMatchers(matchers []Matcher, value interface{}) interface{} {
+	for _, matcher := range matchers {
+		value = matcher(value)
+	}
+	return value
+}
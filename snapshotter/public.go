@@ -0,0 +1,42 @@
+package snapshotter
+
+import "io/ioutil"
+
+// Snapshot is the public representation of a single recorded snapshot
+// value, for tools (like cmd/snapshotctl) that need to inspect snapshot
+// files directly rather than through the test-time Snapshotter API.
+type Snapshot struct {
+	Name   string
+	Values []interface{}
+}
+
+// ReadSnapshotFile reads and decodes a snapshot file from disk, validating
+// its envelope checksum the same way Snapshotter.Verify does.
+func ReadSnapshotFile(path string) ([]Snapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := decodeSnapshotFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]Snapshot, len(decoded))
+	for i, snap := range decoded {
+		snapshots[i] = Snapshot{Name: snap.Name, Values: snap.Values}
+	}
+	return snapshots, nil
+}
+
+// DiffString renders a unified diff between a and b the same way
+// Snapshotter.Verify does when reporting a mismatch.
+func DiffString(a, b interface{}) string {
+	return diffString(a, b)
+}
+
+// CoerceToString renders a value as text the same way the runtime
+// comparator does before diffing.
+func CoerceToString(i interface{}) string {
+	return coerceToString(i)
+}
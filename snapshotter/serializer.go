@@ -0,0 +1,115 @@
+package snapshotter
+
+import (
+	"encoding/json"
+
+	"github.com/kylelemons/godebug/pretty"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Serializer controls how a snapshotted value is encoded to and decoded from
+// the snapshot file. The default is JSONSerializer, which is what
+// Snapshotter used exclusively before WithSerializer existed.
+type Serializer interface {
+	Marshal(interface{}) ([]byte, error)
+	Unmarshal([]byte) (interface{}, error)
+
+	// Ext is the file extension (without a leading dot) associated with this
+	// serializer's encoding, for tooling that wants to name files after it.
+	Ext() string
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) Marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (jsonSerializer) Unmarshal(data []byte) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (jsonSerializer) Ext() string { return "json" }
+
+type yamlSerializer struct{}
+
+func (yamlSerializer) Marshal(value interface{}) ([]byte, error) {
+	return yaml.Marshal(value)
+}
+
+func (yamlSerializer) Unmarshal(data []byte) (interface{}, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (yamlSerializer) Ext() string { return "yaml" }
+
+// prettySerializer formats values with pretty.Sprint rather than
+// round-tripping them. This loses type fidelity entirely, so Unmarshal just
+// hands back the formatted text -- it's meant for human-readable golden
+// files, not for recovering structured data.
+type prettySerializer struct{}
+
+func (prettySerializer) Marshal(value interface{}) ([]byte, error) {
+	// A string is taken to be text this serializer already rendered, so
+	// writing a round-tripped value stores the same text Unmarshal returns.
+	if str, ok := value.(string); ok {
+		return []byte(str), nil
+	}
+	return []byte(pretty.Sprint(value)), nil
+}
+
+func (prettySerializer) Unmarshal(data []byte) (interface{}, error) {
+	return string(data), nil
+}
+
+func (prettySerializer) Ext() string { return "txt" }
+
+var (
+	// JSONSerializer round-trips values through encoding/json. It's the
+	// default used when a Snapshotter has no serializer set.
+	JSONSerializer Serializer = jsonSerializer{}
+
+	// YAMLSerializer round-trips values through YAML, which is more
+	// readable for deeply nested values than JSON.
+	YAMLSerializer Serializer = yamlSerializer{}
+
+	// PrettySerializer formats values as pretty.Sprint text. It is
+	// one-way: Unmarshal returns the formatted string itself rather than
+	// reconstructing the original value, so snapshots taken with it are
+	// compared as rendered text.
+	PrettySerializer Serializer = prettySerializer{}
+)
+
+// serializerRoundTrip marshals then unmarshals value through serializer (for
+// JSONSerializer, a plain encoding/json round trip). Snapshot uses this so
+// the in-memory representation matches what Verify will read back from disk.
+func serializerRoundTrip(serializer Serializer, value interface{}) (interface{}, error) {
+	bytes, err := serializer.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return serializer.Unmarshal(bytes)
+}
+
+// serializerByExt looks up one of the built-in Serializers by its Ext(), for
+// decoding a snapshot file entry that recorded which serializer wrote it. An
+// unrecognized or empty ext (including every file written before
+// WithSerializer existed) falls back to JSONSerializer.
+func serializerByExt(ext string) Serializer {
+	switch ext {
+	case YAMLSerializer.Ext():
+		return YAMLSerializer
+	case PrettySerializer.Ext():
+		return PrettySerializer
+	default:
+		return JSONSerializer
+	}
+}
@@ -0,0 +1,117 @@
+package snapshotter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// fakeT is a minimal T used to drive Snapshotter end-to-end in tests
+// without a nested *testing.T (which would fail the outer test on any
+// Errorf).
+type fakeT struct {
+	name   string
+	errors []string
+}
+
+func (f *fakeT) Name() string { return f.name }
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+func (f *fakeT) Helper() {}
+
+func TestSerializerRoundTripJSON(t *testing.T) {
+	got, err := serializerRoundTrip(JSONSerializer, map[string]interface{}{"a": 1})
+	if err != nil {
+		t.Fatalf("error round tripping: %s", err)
+	}
+	m, ok := got.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected a map, got %T", got)
+	}
+	if m["a"] != float64(1) {
+		t.Fatalf("expected a=1, got %v", m["a"])
+	}
+}
+
+func TestSerializerRoundTripYAML(t *testing.T) {
+	got, err := serializerRoundTrip(YAMLSerializer, map[string]interface{}{"a": 1})
+	if err != nil {
+		t.Fatalf("error round tripping: %s", err)
+	}
+	m, ok := got.(map[interface{}]interface{})
+	if !ok {
+		t.Fatalf("expected a map, got %T", got)
+	}
+	if m["a"] != 1 {
+		t.Fatalf("expected a=1, got %v", m["a"])
+	}
+}
+
+// TestSnapshotterWithYAMLSerializerRoundTripsThroughVerify exercises
+// WithSerializer(YAMLSerializer) end to end: rewrite a non-scalar value to
+// disk, then verify it on a second run, the way a real test suite would.
+func TestSnapshotterWithYAMLSerializerRoundTripsThroughVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshotter-yaml")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("error getting working directory: %s", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("error changing to temp dir: %s", err)
+	}
+	defer os.Chdir(wd)
+
+	value := map[string]interface{}{"a": 1, "b": []interface{}{"x", "y"}}
+
+	*rewrite = true
+	rewriteT := &fakeT{name: "TestYAMLExample"}
+	rewriteSnapshotter := New(rewriteT).WithSerializer(YAMLSerializer)
+	rewriteSnapshotter.Snapshot("value", value)
+	rewriteSnapshotter.Verify()
+	if len(rewriteT.errors) > 0 {
+		t.Fatalf("unexpected errors while rewriting: %v", rewriteT.errors)
+	}
+
+	*rewrite = false
+	verifyT := &fakeT{name: "TestYAMLExample"}
+	verifySnapshotter := New(verifyT).WithSerializer(YAMLSerializer)
+	verifySnapshotter.Snapshot("value", value)
+	verifySnapshotter.Verify()
+	if len(verifyT.errors) > 0 {
+		t.Fatalf("unexpected errors while verifying: %v", verifyT.errors)
+	}
+}
+
+func TestIgnoreFieldsMatcher(t *testing.T) {
+	matcher := IgnoreFields("CreatedAt")
+	value := map[string]interface{}{"CreatedAt": "2020-01-01", "Name": "foo"}
+
+	got := matcher(value).(map[string]interface{})
+	if _, ok := got["CreatedAt"]; ok {
+		t.Fatalf("expected CreatedAt to be removed, got %v", got)
+	}
+	if got["Name"] != "foo" {
+		t.Fatalf("expected Name to survive, got %v", got)
+	}
+	if _, ok := value["CreatedAt"]; !ok {
+		t.Fatalf("expected the original map to be left untouched")
+	}
+}
+
+func TestMatchRegexpMatcher(t *testing.T) {
+	matcher := MatchRegexp(`^id-\d+$`)
+
+	if got := matcher("id-123"); got == "id-123" {
+		t.Fatalf("expected a matching string to be replaced, got %v", got)
+	}
+	if got := matcher("not-an-id"); got != "not-an-id" {
+		t.Fatalf("expected a non-matching string to pass through unchanged, got %v", got)
+	}
+}
@@ -1,7 +1,6 @@
 package snapshotter
 
 import (
-	"encoding/json"
 	"flag"
 	"io/ioutil"
 	"os"
@@ -20,21 +19,14 @@ type T interface {
 
 var rewrite = flag.Bool("rewriteSnapshots", false, "rewrite test data output")
 
-func jsonRoundTrip(value interface{}) (interface{}, error) {
-	bytes, err := json.Marshal(value)
-	if err != nil {
-		return nil, err
-	}
-	var roundtripped interface{}
-	if err := json.Unmarshal(bytes, &roundtripped); err != nil {
-		return nil, err
-	}
-	return roundtripped, nil
-}
-
 type snapshot struct {
 	Name   string
 	Values []interface{}
+
+	// SchemaVersion records the per-snapshot encoding version this value was
+	// written with, separate from the envelope's own Version. See
+	// currentSnapshotSchemaVersion.
+	SchemaVersion int `json:",omitempty"`
 }
 
 // Snapshotter is a utility for writing snapshot tests. In a snapshot, the
@@ -45,6 +37,7 @@ type Snapshotter struct {
 	t              T
 	name           string
 	snapshots      []*snapshot
+	serializer     Serializer
 	SnapshotErrors bool
 }
 
@@ -58,22 +51,51 @@ func NewNamed(t T, name string) *Snapshotter {
 	return &Snapshotter{t: t, name: name}
 }
 
+// WithSerializer sets the Serializer used to encode and decode snapshotted
+// values, overriding the default of JSONSerializer. It returns s so it can
+// be chained off New/NewNamed.
+func (s *Snapshotter) WithSerializer(serializer Serializer) *Snapshotter {
+	s.serializer = serializer
+	return s
+}
+
+func (s *Snapshotter) serializerOrDefault() Serializer {
+	if s.serializer == nil {
+		return JSONSerializer
+	}
+	return s.serializer
+}
+
 // Snapshot records a value for a snapshot test. For the test to pass, all
 // invocations to Snapshot should have the same arguments. All values should be
-// JSON-marshalable.
+// marshalable by the Snapshotter's Serializer (JSON by default).
 func (s *Snapshotter) Snapshot(name string, values ...interface{}) {
+	s.snapshot(name, nil, values)
+}
+
+// SnapshotWithMatchers records a value like Snapshot, but first passes each
+// value through every Matcher, in order. Matchers let callers normalize or
+// ignore dynamic fields -- timestamps, UUIDs, request IDs -- that would
+// otherwise cause the golden file to churn on every run.
+func (s *Snapshotter) SnapshotWithMatchers(name string, matchers []Matcher, values ...interface{}) {
+	s.snapshot(name, matchers, values)
+}
+
+func (s *Snapshotter) snapshot(name string, matchers []Matcher, values []interface{}) {
+	serializer := s.serializerOrDefault()
 	for i, value := range values {
-		roundtripped, err := jsonRoundTrip(value)
+		roundtripped, err := serializerRoundTrip(serializer, value)
 		if err != nil {
 			s.t.Errorf("%s: error roundtripping value %v: %s", name, value, err)
 			return
 		}
-		values[i] = roundtripped
+		values[i] = applyMatchers(matchers, roundtripped)
 	}
 
 	s.snapshots = append(s.snapshots, &snapshot{
-		Name:   name,
-		Values: values,
+		Name:          name,
+		Values:        values,
+		SchemaVersion: currentSnapshotSchemaVersion,
 	})
 }
 
@@ -93,9 +115,10 @@ func (s *Snapshotter) Verify() {
 				return
 			}
 
-			// The file exists, so let's remove it.
-			err := os.Remove(name)
-			if err != nil {
+			// The file exists, so let's remove it. os.Remove is already a
+			// single atomic syscall, so there's no torn state to guard
+			// against here the way there is for a write.
+			if err := os.Remove(name); err != nil {
 				s.t.Errorf("failed to remove the existing snapshot file %s", name)
 			}
 			return
@@ -104,12 +127,27 @@ func (s *Snapshotter) Verify() {
 			s.t.Errorf("error creating testdata directory: %s", err)
 			return
 		}
-		bytes, err := json.MarshalIndent(s.snapshots, "", "  ")
+
+		// Keep a copy of the last good file around so a bad rewrite (e.g. an
+		// interrupted run after the .pending file already synced) can still
+		// be recovered from on the next Verify. Only promote it if it still
+		// decodes: if name is already corrupt, promoting it would overwrite
+		// the one good copy this feature exists to preserve.
+		if existing, err := ioutil.ReadFile(name); err == nil {
+			if _, err := decodeSnapshotFile(existing); err == nil {
+				if err := atomicWriteFile(name+".bak", existing, 0644); err != nil {
+					s.t.Errorf("error writing backup snapshots file: %s", err)
+					return
+				}
+			}
+		}
+
+		bytes, err := encodeSnapshotFile(s.snapshots, s.serializerOrDefault())
 		if err != nil {
 			s.t.Errorf("error marshaling snapshots: %s", err)
 			return
 		}
-		if err := ioutil.WriteFile(name, bytes, 0644); err != nil {
+		if err := atomicWriteFile(name, bytes, 0644); err != nil {
 			s.t.Errorf("error writing snapshots: %s", err)
 			return
 		}
@@ -124,10 +162,20 @@ func (s *Snapshotter) Verify() {
 			s.t.Errorf("error reading snapshots: %s", err)
 			return
 		}
-		var expected []*snapshot
-		if err := json.Unmarshal(bytes, &expected); err != nil {
-			s.t.Errorf("error unmarshaling snapshots: %s", err)
-			return
+		expected, err := decodeSnapshotFile(bytes)
+		if err != nil {
+			bakBytes, bakErr := ioutil.ReadFile(name + ".bak")
+			if bakErr != nil {
+				s.t.Errorf("error reading snapshot file %s: %s", name, err)
+				return
+			}
+			bakExpected, bakErr := decodeSnapshotFile(bakBytes)
+			if bakErr != nil {
+				s.t.Errorf("error reading snapshot file %s: %s", name, err)
+				return
+			}
+			s.t.Errorf("snapshot file %s is corrupt (%s); recovered previous snapshots from %s.bak -- re-run with -rewriteSnapshots to regenerate", name, err, name)
+			expected = bakExpected
 		}
 
 		actual := s.snapshots
@@ -169,6 +217,38 @@ func (s *Snapshotter) Verify() {
 	}
 }
 
+// atomicWriteFile writes data to a ".pending" file next to name, fsyncs it,
+// and renames it over name. Keeping the temp file in the same directory
+// means the final os.Rename is atomic on POSIX, so a test run that's killed
+// or panics mid-write can never leave a truncated snapshot file behind. The
+// pending file is cleaned up on any error path.
+func atomicWriteFile(name string, data []byte, perm os.FileMode) (err error) {
+	pending := name + ".pending"
+	f, err := os.OpenFile(pending, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(pending)
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(pending, name)
+}
+
 func coerceToString(i interface{}) string {
 	if str, ok := i.(string); ok {
 		return str
@@ -0,0 +1,39 @@
+package snapshotter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestAtomicWriteFileSurvivesRenameFailure simulates a failure at the last
+// step of the write: the pending file is successfully opened, written, synced, and
+// closed, and only the final rename fails (forced by pointing name at an
+// existing directory). It checks that the failure is reported, the
+// pending file left over from the write doesn't survive it, and the
+// destination is untouched.
+func TestAtomicWriteFileSurvivesRenameFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshotter-atomic")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "busy")
+	if err := os.Mkdir(name, 0755); err != nil {
+		t.Fatalf("error seeding destination directory: %s", err)
+	}
+
+	if err := atomicWriteFile(name, []byte(`new`), 0644); err == nil {
+		t.Fatalf("expected atomicWriteFile to fail renaming over a directory")
+	}
+
+	if _, err := os.Stat(name + ".pending"); !os.IsNotExist(err) {
+		t.Fatalf("expected no pending file to be left behind, got err=%v", err)
+	}
+	info, err := os.Stat(name)
+	if err != nil || !info.IsDir() {
+		t.Fatalf("expected destination to be left untouched, got info=%v err=%v", info, err)
+	}
+}